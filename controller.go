@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// problemRecord tracks when a pod was first seen stuck and under which
+// reason, so the podsStuck gauge can be decremented under the same labels
+// it was incremented with.
+type problemRecord struct {
+	since  time.Time
+	reason string
+}
+
+// PodController watches Pods via a shared informer and serializes stuck-pod
+// decisions per pod key through a rate-limiting workqueue, mirroring the
+// list/process pattern used by core controllers (endpoints, kubelet sync loop).
+type PodController struct {
+	clientset   kubernetes.Interface
+	lister      corelisters.PodLister
+	informer    cache.SharedIndexInformer
+	queue       workqueue.RateLimitingInterface
+	detectors   []PodProblemDetector
+	notifier    Notifier
+	remediator  *Remediator
+	broadcaster events.EventBroadcasterAdapter
+	health      *healthState
+	watchedNs   string // "" for a cluster-wide watch; reported to health.setSynced
+
+	mu              sync.Mutex
+	podProblemTimes map[string]problemRecord
+}
+
+// NewPodController wires up a controller watching watchedNs ("" for
+// cluster-wide) from factory. ctx scopes the recorder's event broadcaster:
+// Run shuts it down when ctx is done instead of leaving it running for the
+// lifetime of the process, since under leader election a new PodController
+// (and a new broadcaster) is built on every lease acquisition.
+func NewPodController(ctx context.Context, clientset kubernetes.Interface, factory informers.SharedInformerFactory, health *healthState, watchedNs string) *PodController {
+	podInformer := factory.Core().V1().Pods()
+
+	broadcaster, recorder := newEventRecorder(clientset, ctx.Done())
+
+	c := &PodController{
+		clientset:       clientset,
+		lister:          podInformer.Lister(),
+		informer:        podInformer.Informer(),
+		queue:           workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		detectors:       buildDetectors(),
+		notifier:        buildNotifier(),
+		remediator:      NewRemediator(clientset, recorder),
+		broadcaster:     broadcaster,
+		health:          health,
+		watchedNs:       watchedNs,
+		podProblemTimes: make(map[string]problemRecord),
+	}
+
+	podInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: c.enqueue,
+		UpdateFunc: func(_, newObj interface{}) {
+			c.enqueue(newObj)
+		},
+		DeleteFunc: c.enqueue,
+	})
+
+	if err := c.informer.SetWatchErrorHandler(func(r *cache.Reflector, err error) {
+		listErrorsTotal.Inc()
+		cache.DefaultWatchErrorHandler(r, err)
+	}); err != nil {
+		slog.Error("failed to set watch error handler", "error", err)
+	}
+
+	return c
+}
+
+func (c *PodController) enqueue(obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		slog.Error("enqueue: cannot compute key", "error", err)
+		return
+	}
+	c.queue.Add(key)
+}
+
+// Run waits for the informer cache to sync and then processes the workqueue
+// with the given number of workers until ctx is cancelled.
+func (c *PodController) Run(ctx context.Context, workers int) error {
+	defer c.queue.ShutDown()
+	defer c.broadcaster.Shutdown()
+
+	slog.Info("starting pod controller, waiting for cache sync")
+	if !cache.WaitForCacheSync(ctx.Done(), c.informer.HasSynced) {
+		return fmt.Errorf("failed to wait for caches to sync")
+	}
+	slog.Info("pod controller cache synced", "workers", workers, "namespace", c.watchedNs)
+	c.health.setSynced(c.watchedNs)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			wait.Until(func() { c.runWorker(ctx) }, time.Second, ctx.Done())
+		}()
+	}
+
+	<-ctx.Done()
+	wg.Wait()
+	return nil
+}
+
+func (c *PodController) runWorker(ctx context.Context) {
+	for c.processNextItem(ctx) {
+	}
+}
+
+func (c *PodController) processNextItem(ctx context.Context) bool {
+	key, shutdown := c.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer c.queue.Done(key)
+
+	if err := c.syncPod(ctx, key.(string)); err != nil {
+		slog.Warn("sync failed, requeueing", "pod", key, "error", err, "attempts", c.queue.NumRequeues(key))
+		c.queue.AddRateLimited(key)
+		return true
+	}
+
+	c.queue.Forget(key)
+	return true
+}
+
+// syncPod reconciles a single pod identified by its "namespace/name" key:
+// it checks whether the pod is currently stuck and, once it has been stuck
+// for longer than pendingTimeout, fires the notifier and clears the timer.
+func (c *PodController) syncPod(ctx context.Context, key string) error {
+	ns, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+
+	c.health.recordCheck()
+	podsCheckedTotal.WithLabelValues(ns).Inc()
+
+	pod, err := c.lister.Pods(ns).Get(name)
+	if apierrors.IsNotFound(err) {
+		c.mu.Lock()
+		c.clearProblem(key)
+		c.mu.Unlock()
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	stuck, reason := c.detectStuck(pod, now)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !stuck {
+		// если починился — удаляем из карты
+		c.clearProblem(key)
+		return nil
+	}
+
+	record, seen := c.podProblemTimes[key]
+	if !seen {
+		// первая фиксация проблемы — запоминаем время
+		c.podProblemTimes[key] = problemRecord{since: now, reason: reason}
+		podsStuck.WithLabelValues(ns, reason).Inc()
+		slog.Info("problem detected, starting timer", "pod", name, "namespace", ns, "reason", reason)
+		return nil
+	}
+
+	if now.Sub(record.since) < pendingTimeout {
+		return nil
+	}
+
+	duration := now.Sub(record.since)
+	correlationID := fmt.Sprintf("%s-%d", key, now.UnixNano())
+
+	slog.Info("pod confirmed stuck, notifying and remediating",
+		"pod", name, "namespace", ns, "reason", reason,
+		"duration_seconds", duration.Seconds(), "correlation_id", correlationID)
+
+	ps := PodStatus{
+		Name:      pod.Name,
+		Status:    string(pod.Status.Phase),
+		Namespace: pod.Namespace,
+		Duration:  fmt.Sprintf("%.0f seconds", duration.Seconds()),
+		Timestamp: now,
+	}
+
+	notifyStart := time.Now()
+	if err := c.notifier.Notify(ctx, ps, reason); err != nil {
+		notifyDuration.WithLabelValues("error").Observe(time.Since(notifyStart).Seconds())
+		slog.Warn("notify failed", "pod", name, "namespace", ns, "reason", reason,
+			"action", "notify", "correlation_id", correlationID, "error", err)
+	} else {
+		notifyDuration.WithLabelValues("ok").Observe(time.Since(notifyStart).Seconds())
+		slog.Info("notify OK", "pod", name, "namespace", ns, "reason", reason,
+			"action", "notify", "correlation_id", correlationID)
+	}
+
+	if err := c.remediator.Remediate(ctx, pod, reason); err != nil {
+		slog.Warn("remediation failed", "pod", name, "namespace", ns, "reason", reason,
+			"action", string(c.remediator.action), "correlation_id", correlationID, "error", err)
+	}
+
+	// сбрасываем таймер
+	c.clearProblem(key)
+	return nil
+}
+
+// clearProblem removes key's problem record, decrementing the podsStuck
+// gauge under the labels it was originally incremented with. Caller must
+// hold c.mu.
+func (c *PodController) clearProblem(key string) {
+	record, ok := c.podProblemTimes[key]
+	if !ok {
+		return
+	}
+	ns, _, err := cache.SplitMetaNamespaceKey(key)
+	if err == nil {
+		podsStuck.WithLabelValues(ns, record.reason).Dec()
+	}
+	delete(c.podProblemTimes, key)
+}
+
+// detectStuck runs every configured detector in order and returns the first
+// match; detector order therefore acts as a priority among problem reasons.
+func (c *PodController) detectStuck(pod *corev1.Pod, now time.Time) (bool, string) {
+	for _, d := range c.detectors {
+		if stuck, reason := d.Detect(pod, now); stuck {
+			return true, reason
+		}
+	}
+	return false, ""
+}
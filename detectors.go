@@ -0,0 +1,203 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// PodProblemDetector inspects a pod at a point in time and reports whether
+// it is stuck, along with a short machine-readable reason that flows through
+// to Notifier.Notify. Detectors are independently enable-able via env vars so a
+// cluster operator can turn individual checks on/off without a code change;
+// the env vars are read when the controller is built.
+type PodProblemDetector interface {
+	Detect(pod *corev1.Pod, now time.Time) (bool, string)
+}
+
+// containerWaitingDetector flags pods with a container currently waiting on
+// one of a set of known-bad reasons, once the pod is older than timeout. The
+// time measured is the pod's age, not how long the container has been waiting.
+type containerWaitingDetector struct {
+	reasons []string
+	timeout time.Duration
+}
+
+func (d *containerWaitingDetector) Detect(pod *corev1.Pod, now time.Time) (bool, string) {
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.State.Waiting == nil {
+			continue
+		}
+		for _, reason := range d.reasons {
+			if cs.State.Waiting.Reason == reason && now.Sub(pod.CreationTimestamp.Time) > d.timeout {
+				return true, cs.State.Waiting.Reason
+			}
+		}
+	}
+	return false, ""
+}
+
+// pendingUnschedulableDetector flags pods that have sat in Pending with
+// PodScheduled=False for longer than timeout, i.e. the scheduler can't place them.
+type pendingUnschedulableDetector struct {
+	timeout time.Duration
+}
+
+func (d *pendingUnschedulableDetector) Detect(pod *corev1.Pod, now time.Time) (bool, string) {
+	if pod.Status.Phase != corev1.PodPending {
+		return false, ""
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodScheduled && cond.Status == corev1.ConditionFalse {
+			if now.Sub(cond.LastTransitionTime.Time) > d.timeout {
+				return true, "Unschedulable"
+			}
+		}
+	}
+	return false, ""
+}
+
+// terminatingDetector flags pods stuck in deletion (DeletionTimestamp set,
+// but still present) for longer than timeout, e.g. a finalizer wedged.
+type terminatingDetector struct {
+	timeout time.Duration
+}
+
+func (d *terminatingDetector) Detect(pod *corev1.Pod, now time.Time) (bool, string) {
+	if pod.DeletionTimestamp == nil {
+		return false, ""
+	}
+	if now.Sub(pod.DeletionTimestamp.Time) > d.timeout {
+		return true, "Terminating"
+	}
+	return false, ""
+}
+
+// restartRateDetector flags pods whose total RestartCount grows by at least
+// threshold within a sliding window, catching crash loops that never settle
+// on a single waiting reason long enough for containerWaitingDetector to fire.
+type restartRateDetector struct {
+	threshold int32
+	window    time.Duration
+
+	mu    sync.Mutex
+	state map[string]restartSample
+}
+
+type restartSample struct {
+	count int32
+	at    time.Time
+}
+
+func newRestartRateDetector(threshold int32, window time.Duration) *restartRateDetector {
+	return &restartRateDetector{
+		threshold: threshold,
+		window:    window,
+		state:     make(map[string]restartSample),
+	}
+}
+
+func (d *restartRateDetector) Detect(pod *corev1.Pod, now time.Time) (bool, string) {
+	var restarts int32
+	for _, cs := range pod.Status.ContainerStatuses {
+		restarts += cs.RestartCount
+	}
+
+	key := pod.Namespace + "/" + pod.Name
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	prev, ok := d.state[key]
+	if !ok || now.Sub(prev.at) > d.window {
+		d.state[key] = restartSample{count: restarts, at: now}
+		return false, ""
+	}
+
+	if restarts-prev.count >= d.threshold {
+		d.state[key] = restartSample{count: restarts, at: now}
+		return true, "RestartingTooFast"
+	}
+	return false, ""
+}
+
+func getEnvBool(key string, def bool) bool {
+	str := os.Getenv(key)
+	if str == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(str)
+	if err != nil {
+		slog.Warn("invalid env value, falling back to default", "key", key, "value", str, "default", def)
+		return def
+	}
+	return b
+}
+
+func getMinutesEnv(key string, def time.Duration) time.Duration {
+	str := getEnv(key, "")
+	if str == "" {
+		return def
+	}
+	mins, err := strconv.Atoi(str)
+	if err != nil || mins <= 0 {
+		slog.Warn("invalid env value, falling back to default", "key", key, "value", str, "default", def.String())
+		return def
+	}
+	return time.Duration(mins) * time.Minute
+}
+
+func getIntEnv(key string, def int32) int32 {
+	str := getEnv(key, "")
+	if str == "" {
+		return def
+	}
+	n, err := strconv.Atoi(str)
+	if err != nil || n <= 0 {
+		slog.Warn("invalid env value, falling back to default", "key", key, "value", str, "default", def)
+		return def
+	}
+	return int32(n)
+}
+
+// buildDetectors assembles the enabled set of PodProblemDetector rules from
+// env config. containerWaitingDetector is on by default to preserve prior
+// behaviour; the rest are opt-in since they change remediation volume.
+func buildDetectors() []PodProblemDetector {
+	var detectors []PodProblemDetector
+
+	if getEnvBool("DETECT_CONTAINER_WAITING", true) {
+		detectors = append(detectors, &containerWaitingDetector{
+			reasons: []string{
+				"ContainerCreating",
+				"CrashLoopBackOff",
+				"ImagePullBackOff",
+				"ErrImagePull",
+				"CreateContainerConfigError",
+			},
+			timeout: pendingTimeout,
+		})
+	}
+
+	if getEnvBool("DETECT_PENDING_UNSCHEDULABLE", false) {
+		timeout := getMinutesEnv("PENDING_TIMEOUT", pendingTimeout)
+		detectors = append(detectors, &pendingUnschedulableDetector{timeout: timeout})
+	}
+
+	if getEnvBool("DETECT_TERMINATING", false) {
+		timeout := getMinutesEnv("TERMINATING_TIMEOUT", 10*time.Minute)
+		detectors = append(detectors, &terminatingDetector{timeout: timeout})
+	}
+
+	if getEnvBool("DETECT_RESTART_RATE", false) {
+		threshold := getIntEnv("RESTART_RATE_THRESHOLD", 5)
+		window := getMinutesEnv("RESTART_RATE_WINDOW", 10*time.Minute)
+		detectors = append(detectors, newRestartRateDetector(threshold, window))
+	}
+
+	return detectors
+}
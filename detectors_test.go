@@ -0,0 +1,78 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func restartingPod(ns, name string, restarts int32) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{
+				{RestartCount: restarts},
+			},
+		},
+	}
+}
+
+func TestRestartRateDetector(t *testing.T) {
+	d := newRestartRateDetector(3, 5*time.Minute)
+	pod := restartingPod("default", "flaky", 0)
+	base := time.Unix(1_700_000_000, 0)
+
+	if stuck, _ := d.Detect(pod, base); stuck {
+		t.Fatal("first observation should only seed the baseline, not report stuck")
+	}
+
+	steps := []struct {
+		name       string
+		restarts   int32
+		at         time.Time
+		wantStuck  bool
+		wantReason string
+	}{
+		{"below threshold stays quiet", 2, base.Add(time.Minute), false, ""},
+		{"crossing threshold trips", 6, base.Add(2 * time.Minute), true, "RestartingTooFast"},
+	}
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			pod.Status.ContainerStatuses[0].RestartCount = step.restarts
+			stuck, reason := d.Detect(pod, step.at)
+			if stuck != step.wantStuck {
+				t.Fatalf("Detect() stuck = %v, want %v", stuck, step.wantStuck)
+			}
+			if reason != step.wantReason {
+				t.Fatalf("Detect() reason = %q, want %q", reason, step.wantReason)
+			}
+		})
+	}
+}
+
+func TestRestartRateDetectorWindowExpiry(t *testing.T) {
+	d := newRestartRateDetector(3, 5*time.Minute)
+	pod := restartingPod("default", "flaky", 0)
+	base := time.Unix(1_700_000_000, 0)
+
+	if stuck, _ := d.Detect(pod, base); stuck {
+		t.Fatal("first observation should only seed the baseline")
+	}
+
+	// A big jump observed after the window has elapsed resets the baseline
+	// instead of counting as a restart burst, since the detector can no
+	// longer attribute it to the same sliding window.
+	pod.Status.ContainerStatuses[0].RestartCount = 10
+	if stuck, _ := d.Detect(pod, base.Add(10*time.Minute)); stuck {
+		t.Fatal("jump observed after the window expired should reset the baseline, not trip")
+	}
+
+	// Having reset, a further jump within the new window trips again.
+	pod.Status.ContainerStatuses[0].RestartCount = 14
+	stuck, reason := d.Detect(pod, base.Add(11*time.Minute))
+	if !stuck || reason != "RestartingTooFast" {
+		t.Fatalf("Detect() = (%v, %q), want (true, \"RestartingTooFast\")", stuck, reason)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/client-go/tools/record"
+)
+
+// runWithLeaderElection only invokes run (the check loop) while this process
+// holds the LeaseLock, so N replicas of the watchdog can run for HA but just
+// one of them checks/notifies/remediates at a time. health is updated on
+// every leadership transition so /healthz and /readyz can tell a standby
+// replica apart from a leader that has stopped reconciling.
+func runWithLeaderElection(ctx context.Context, clientset kubernetes.Interface, health *healthState, run func(context.Context)) error {
+	health.setLeaderElection(true)
+
+	leaseName := getEnv("LEASE_NAME", "k8s-watchdog")
+	leaseNamespace := getEnv("LEASE_NAMESPACE", defaultLeaseNamespace())
+	identity := leaderIdentity()
+
+	leaseDuration := getSecondsEnv("LEASE_DURATION_SECONDS", 15*time.Second)
+	renewDeadline := getSecondsEnv("RENEW_DEADLINE_SECONDS", 10*time.Second)
+	retryPeriod := getSecondsEnv("RETRY_PERIOD_SECONDS", 2*time.Second)
+
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: clientset.CoreV1().Events(leaseNamespace)})
+	recorder := broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "k8s-watchdog"})
+
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{
+			Name:      leaseName,
+			Namespace: leaseNamespace,
+		},
+		Client: clientset.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity:      identity,
+			EventRecorder: recorder,
+		},
+	}
+
+	slog.Info("leader election enabled", "lease_namespace", leaseNamespace, "lease_name", leaseName, "identity", identity)
+
+	lec := leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		ReleaseOnCancel: true,
+		LeaseDuration:   leaseDuration,
+		RenewDeadline:   renewDeadline,
+		RetryPeriod:     retryPeriod,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(leCtx context.Context) {
+				slog.Info("became leader, starting checks", "identity", identity)
+				health.setLeading(true)
+				run(leCtx)
+			},
+			OnStoppedLeading: func() {
+				slog.Info("lost leadership, stopping checks", "identity", identity)
+				health.setLeading(false)
+				health.resetSynced()
+			},
+			OnNewLeader: func(currentID string) {
+				if currentID != identity {
+					slog.Info("new leader elected", "leader", currentID)
+				}
+			},
+		},
+	}
+
+	// RunOrDie returns once the lease is lost; campaign again as a standby
+	// until ctx is cancelled.
+	for ctx.Err() == nil {
+		leaderelection.RunOrDie(ctx, lec)
+	}
+
+	return ctx.Err()
+}
+
+func leaderIdentity() string {
+	if id := os.Getenv("POD_NAME"); id != "" {
+		return id
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		return "k8s-watchdog-unknown"
+	}
+	return host
+}
+
+func getSecondsEnv(key string, def time.Duration) time.Duration {
+	str := os.Getenv(key)
+	if str == "" {
+		return def
+	}
+	secs, err := time.ParseDuration(str + "s")
+	if err != nil {
+		slog.Warn("invalid env value, falling back to default", "key", key, "value", str, "default", def.String())
+		return def
+	}
+	return secs
+}
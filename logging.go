@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// rotatingFile is an io.Writer over a single log file that rotates itself
+// once it crosses maxSizeByte, keeping up to maxBackups numbered backups and
+// pruning any older than maxAge. It also supports reopen() so an external
+// log rotator can signal SIGHUP and have us pick up a renamed/truncated file.
+type rotatingFile struct {
+	mu          sync.Mutex
+	path        string
+	maxSizeByte int64
+	maxBackups  int
+	maxAge      time.Duration
+	file        *os.File
+	size        int64
+}
+
+func newRotatingFile(path string, maxSizeMB, maxBackups, maxAgeDays int) (*rotatingFile, error) {
+	rf := &rotatingFile{
+		path:        path,
+		maxSizeByte: int64(maxSizeMB) * 1024 * 1024,
+		maxBackups:  maxBackups,
+		maxAge:      time.Duration(maxAgeDays) * 24 * time.Hour,
+	}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+func (rf *rotatingFile) open() error {
+	f, err := os.OpenFile(rf.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	rf.file = f
+	rf.size = info.Size()
+	return nil
+}
+
+func (rf *rotatingFile) Write(p []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.maxSizeByte > 0 && rf.size+int64(len(p)) > rf.maxSizeByte {
+		if err := rf.rotateLocked(); err != nil {
+			fmt.Fprintf(os.Stderr, "log rotate error: %v\n", err)
+		}
+	}
+
+	n, err := rf.file.Write(p)
+	rf.size += int64(n)
+	return n, err
+}
+
+func (rf *rotatingFile) rotateLocked() error {
+	rf.file.Close()
+	rf.shiftBackupsLocked()
+	if err := rf.open(); err != nil {
+		return err
+	}
+	rf.size = 0
+	return nil
+}
+
+func (rf *rotatingFile) shiftBackupsLocked() {
+	if rf.maxBackups <= 0 {
+		os.Remove(rf.path)
+		return
+	}
+
+	oldest := fmt.Sprintf("%s.%d", rf.path, rf.maxBackups)
+	os.Remove(oldest)
+	for i := rf.maxBackups - 1; i >= 1; i-- {
+		src := fmt.Sprintf("%s.%d", rf.path, i)
+		dst := fmt.Sprintf("%s.%d", rf.path, i+1)
+		if _, err := os.Stat(src); err == nil {
+			os.Rename(src, dst)
+		}
+	}
+	os.Rename(rf.path, rf.path+".1")
+	rf.pruneOldBackupsLocked()
+}
+
+func (rf *rotatingFile) pruneOldBackupsLocked() {
+	if rf.maxAge <= 0 {
+		return
+	}
+	cutoff := time.Now().Add(-rf.maxAge)
+	for i := 1; i <= rf.maxBackups; i++ {
+		name := fmt.Sprintf("%s.%d", rf.path, i)
+		info, err := os.Stat(name)
+		if err != nil {
+			continue
+		}
+		if info.ModTime().Before(cutoff) {
+			os.Remove(name)
+		}
+	}
+}
+
+// reopen closes and reopens the underlying file in place, for SIGHUP-driven
+// rotation coordination with an external rotator (e.g. logrotate copytruncate).
+func (rf *rotatingFile) reopen() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.file != nil {
+		rf.file.Close()
+	}
+	return rf.open()
+}
+
+func (rf *rotatingFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.file == nil {
+		return nil
+	}
+	return rf.file.Close()
+}
+
+// setupLogging configures the package-level logger as JSON (or text) output
+// mirrored to stdout and a rotating log file, and wires SIGHUP to reopen the
+// file. It returns a cleanup func to call on shutdown.
+func setupLogging() func() {
+	path := getEnv("LOG_FILE", "watchdog.log")
+	maxSizeMB := int(getIntEnv("LOG_MAX_SIZE_MB", 100))
+	maxBackups := int(getIntEnv("LOG_MAX_BACKUPS", 5))
+	maxAgeDays := int(getIntEnv("LOG_MAX_AGE_DAYS", 28))
+	format := getEnv("LOG_FORMAT", "json")
+
+	rf, err := newRotatingFile(path, maxSizeMB, maxBackups, maxAgeDays)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "open log file %q error: %v\n", path, err)
+		os.Exit(1)
+	}
+
+	writer := io.MultiWriter(os.Stdout, rf)
+
+	opts := &slog.HandlerOptions{Level: slog.LevelInfo}
+	var handler slog.Handler
+	if format == "text" {
+		handler = slog.NewTextHandler(writer, opts)
+	} else {
+		handler = slog.NewJSONHandler(writer, opts)
+	}
+	slog.SetDefault(slog.New(handler))
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGHUP)
+	go func() {
+		for range sigCh {
+			if err := rf.reopen(); err != nil {
+				fmt.Fprintf(os.Stderr, "log reopen error: %v\n", err)
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(sigCh)
+		rf.Close()
+	}
+}
@@ -2,17 +2,19 @@ package main
 
 import (
 	"context"
-	"fmt"
-	"io"
-	"log"
+	"log/slog"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
-	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -28,14 +30,44 @@ type PodStatus struct {
 }
 
 var (
-	namespace       = getEnv("NAMESPACE", "default")
-	labelSelector   = os.Getenv("LABEL_SELECTOR") // опционально: например "app=k8s-watchdog"
-	pendingTimeout  = getPendingTimeout()
-	checkInterval   = getCheckInterval()
-	logFile         *os.File
-	podProblemTimes = make(map[string]time.Time)
+	// namespace is "default" unless NAMESPACE is set; it may be "all" for
+	// cluster-wide watching or a comma-separated list of namespaces, see
+	// parseNamespaces.
+	namespace      = getEnv("NAMESPACE", "default")
+	labelSelector  = os.Getenv("LABEL_SELECTOR") // опционально: например "app=k8s-watchdog"
+	pendingTimeout = getPendingTimeout()
+	resyncPeriod   = getCheckInterval()
 )
 
+// parseNamespaces interprets the NAMESPACE env value: "all" means watch
+// every namespace cluster-wide (reported as nil, which runChecks turns into
+// a single informer factory with no namespace restriction);
+// anything else is a comma-separated list of namespaces to watch, each
+// through its own informer factory.
+func parseNamespaces(raw string) []string {
+	if raw == "all" {
+		return nil
+	}
+	var out []string
+	for _, ns := range strings.Split(raw, ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			out = append(out, ns)
+		}
+	}
+	return out
+}
+
+// defaultLeaseNamespace picks the namespace the leader-election Lease lives
+// in when LEASE_NAMESPACE isn't set explicitly. A Lease needs exactly one
+// namespace, so cluster-wide or multi-namespace watching falls back to
+// "default" rather than passing "all" or a CSV straight through.
+func defaultLeaseNamespace() string {
+	if ns := parseNamespaces(namespace); len(ns) == 1 {
+		return ns[0]
+	}
+	return "default"
+}
+
 func getEnv(key, def string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
@@ -47,32 +79,22 @@ func getPendingTimeout() time.Duration {
 	str := getEnv("PENDING_TIMEOUT", "5") // минуты
 	mins, err := strconv.Atoi(str)
 	if err != nil || mins <= 0 {
-		log.Printf("Invalid PENDING_TIMEOUT=%q, fallback to 5m", str)
+		slog.Warn("invalid PENDING_TIMEOUT, falling back to default", "value", str, "default", "5m")
 		return 5 * time.Minute
 	}
 	return time.Duration(mins) * time.Minute
 }
 
 func getCheckInterval() time.Duration {
-	str := getEnv("CHECK_INTERVAL", "30") // секунды
+	str := getEnv("CHECK_INTERVAL", "30") // секунды; теперь используется как период ресинка информера
 	secs, err := strconv.Atoi(str)
 	if err != nil || secs <= 0 {
-		log.Printf("Invalid CHECK_INTERVAL=%q, fallback to 30s", str)
+		slog.Warn("invalid CHECK_INTERVAL, falling back to default", "value", str, "default", "30s")
 		return 30 * time.Second
 	}
 	return time.Duration(secs) * time.Second
 }
 
-func setupLogging() {
-	var err error
-	logFile, err = os.OpenFile("watchdog.log", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		log.Fatalf("open log file error: %v", err)
-	}
-	log.SetOutput(io.MultiWriter(os.Stdout, logFile))
-	log.SetFlags(log.LstdFlags | log.Lshortfile)
-}
-
 func kubeConfig() *rest.Config {
 	// Сначала пробуем in-cluster
 	cfg, err := rest.InClusterConfig()
@@ -83,104 +105,97 @@ func kubeConfig() *rest.Config {
 	kubeconfig := filepath.Join(homedir.HomeDir(), ".kube", "config")
 	cfg, err = clientcmd.BuildConfigFromFlags("", kubeconfig)
 	if err != nil {
-		log.Fatalf("create kubeconfig error: %v", err)
+		slog.Error("create kubeconfig error", "error", err)
+		os.Exit(1)
 	}
 	return cfg
 }
 
 func main() {
-	setupLogging()
-	defer logFile.Close()
+	closeLog := setupLogging()
+	defer closeLog()
 
-	log.Printf("Watchdog: namespace=%s, labelSelector=%q, timeout=%s, interval=%s",
-		namespace, labelSelector, pendingTimeout, checkInterval)
+	slog.Info("watchdog starting",
+		"namespace", namespace, "label_selector", labelSelector,
+		"pending_timeout", pendingTimeout.String(), "resync_period", resyncPeriod.String())
 
 	cfg := kubeConfig()
 	clientset, err := kubernetes.NewForConfig(cfg)
 	if err != nil {
-		log.Fatalf("create clientset error: %v", err)
+		slog.Error("create clientset error", "error", err)
+		os.Exit(1)
 	}
 
-	t := time.NewTicker(checkInterval)
-	defer t.Stop()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-	for {
-		checkPods(clientset)
-		<-t.C
-	}
-}
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		slog.Info("received shutdown signal, stopping controller")
+		cancel()
+	}()
 
-func checkPods(clientset *kubernetes.Clientset) {
-	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
-	defer cancel()
+	health := &healthState{}
+	startMetricsServer(getEnv("METRICS_ADDR", ":9090"), health, 3*resyncPeriod)
 
-	listOpts := metav1.ListOptions{}
-	if labelSelector != "" {
-		listOpts.LabelSelector = labelSelector
-	}
+	tweakListOptions := informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+		if labelSelector != "" {
+			opts.LabelSelector = labelSelector
+		}
+	})
+
+	// watchNamespace runs one informer factory and controller scoped to ns
+	// ("" for cluster-wide) until runCtx is cancelled.
+	watchNamespace := func(runCtx context.Context, ns string) {
+		var opts []informers.SharedInformerOption
+		opts = append(opts, tweakListOptions)
+		if ns != "" {
+			opts = append(opts, informers.WithNamespace(ns))
+		}
+		factory := informers.NewSharedInformerFactoryWithOptions(clientset, resyncPeriod, opts...)
 
-	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, listOpts)
-	if err != nil {
-		log.Printf("list pods error: %v", err)
-		return
-	}
+		controller := NewPodController(runCtx, clientset, factory, health, ns)
 
-	now := time.Now()
-	for _, pod := range pods.Items {
-		stuck, reason := isStuckInContainerCreating(&pod, pendingTimeout)
-		if stuck {
-			if firstSeen, ok := podProblemTimes[pod.Name]; !ok {
-				// первая фиксация проблемы — запоминаем время
-				podProblemTimes[pod.Name] = now
-				log.Printf("Problem detected: %s (%s) — starting timer", pod.Name, reason)
-			} else if now.Sub(firstSeen) >= pendingTimeout {
-				log.Printf("Restarting pod %s (stuck %s, reason=%s)", pod.Name, now.Sub(firstSeen), reason)
-
-				ps := PodStatus{
-					Name:      pod.Name,
-					Status:    string(pod.Status.Phase),
-					Namespace: pod.Namespace,
-					Duration:  fmt.Sprintf("%.0f seconds", now.Sub(firstSeen).Seconds()),
-					Timestamp: now,
-				}
-				// Логирование
-				log.Printf("Problem pod details: %+v", ps)
-
-				// Дергаем ручку(пока заглушка)
-				if err := notifyAPI(ctx, pod.Namespace, pod.Name, reason); err != nil {
-					log.Printf("notify error for pod %s: %v", pod.Name, err)
-				} else {
-					log.Printf("notify OK for pod %s", pod.Name)
-				}
-				// сбрасываем таймер
-				delete(podProblemTimes, pod.Name)
-			}
-		} else {
-			// если починился — удаляем из карты
-			delete(podProblemTimes, pod.Name)
+		factory.Start(runCtx.Done())
+
+		if err := controller.Run(runCtx, 2); err != nil {
+			slog.Error("controller exited with error", "namespace", ns, "error", err)
 		}
 	}
-}
 
-func isStuckInContainerCreating(pod *corev1.Pod, timeout time.Duration) (bool, string) {
-	for _, cs := range pod.Status.ContainerStatuses {
-		if cs.State.Waiting != nil && cs.State.Waiting.Reason == "ContainerCreating" {
-			if time.Since(pod.CreationTimestamp.Time) > timeout {
-				return true, "ContainerCreating"
+	runChecks := func(runCtx context.Context) {
+		namespaces := parseNamespaces(namespace)
+		health.setExpectedSynced(len(namespaces))
+
+		if len(namespaces) <= 1 {
+			ns := ""
+			if len(namespaces) == 1 {
+				ns = namespaces[0]
 			}
+			watchNamespace(runCtx, ns)
+			return
+		}
+
+		var wg sync.WaitGroup
+		for _, ns := range namespaces {
+			wg.Add(1)
+			go func(ns string) {
+				defer wg.Done()
+				watchNamespace(runCtx, ns)
+			}(ns)
 		}
+		wg.Wait()
 	}
-	return false, ""
-}
 
-// notifyAPI — заглушка: здесь позже дергаем ваш внешний API.
-// Сейчас просто логируем и ждём ~200мс, имитируя сетевой вызов.
-func notifyAPI(ctx context.Context, ns, podName, reason string) error {
-	log.Printf("[MOCK] notify external API: namespace=%s pod=%s reason=%s", ns, podName, reason)
-	select {
-	case <-time.After(200 * time.Millisecond):
-		return nil
-	case <-ctx.Done():
-		return ctx.Err()
+	if getEnvBool("LEADER_ELECTION", false) {
+		if err := runWithLeaderElection(ctx, clientset, health, runChecks); err != nil {
+			slog.Error("leader election exited with error", "error", err)
+			os.Exit(1)
+		}
+		return
 	}
+
+	runChecks(ctx)
 }
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseNamespaces(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want []string
+	}{
+		{"all sentinel means cluster-wide", "all", nil},
+		{"single namespace", "default", []string{"default"}},
+		{"comma separated list", "team-a,team-b", []string{"team-a", "team-b"}},
+		{"whitespace and empty entries are trimmed and dropped", " team-a ,, team-b ", []string{"team-a", "team-b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseNamespaces(tt.raw)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("parseNamespaces(%q) = %v, want %v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultLeaseNamespace(t *testing.T) {
+	orig := namespace
+	defer func() { namespace = orig }()
+
+	tests := []struct {
+		name string
+		ns   string
+		want string
+	}{
+		{"single namespace is reused for the lease", "prod", "prod"},
+		{"cluster-wide falls back to default", "all", "default"},
+		{"multi-namespace list falls back to default", "team-a,team-b", "default"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			namespace = tt.ns
+			if got := defaultLeaseNamespace(); got != tt.want {
+				t.Fatalf("defaultLeaseNamespace() with NAMESPACE=%q = %q, want %q", tt.ns, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	podsCheckedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "watchdog_pods_checked_total",
+		Help: "Total number of pod reconciliations processed by the watchdog.",
+	}, []string{"namespace"})
+
+	podsStuck = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "watchdog_pods_stuck",
+		Help: "Pods currently considered stuck by the watchdog, by reason.",
+	}, []string{"namespace", "reason"})
+
+	remediationTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "watchdog_remediation_total",
+		Help: "Total number of remediation actions taken, by action and result.",
+	}, []string{"action", "result"})
+
+	notifyDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "watchdog_notify_duration_seconds",
+		Help:    "Time spent delivering a notification to the configured sinks.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"result"})
+
+	listErrorsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "watchdog_list_errors_total",
+		Help: "Total number of pod list/watch errors observed by the informer.",
+	})
+
+	lastCheckTimestamp = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "watchdog_last_check_timestamp_seconds",
+		Help: "Unix timestamp of the last pod reconciliation handled by the watchdog.",
+	})
+)
+
+// healthState backs /healthz and /readyz: readiness needs the informer
+// cache(s) synced, liveness needs the reconcile loop to have run recently.
+// With LEADER_ELECTION enabled, standby replicas never sync a cache or run
+// a reconcile loop by design, so they report a distinct "standing by" OK
+// state instead of looking perpetually unhealthy. Sync state is tracked per
+// namespace (rather than a single bool) so multi-namespace mode only
+// reports ready once every watched namespace's cache has synced, and is
+// reset on every lease loss so a replica that regains leadership doesn't
+// report readiness left over from its previous term.
+type healthState struct {
+	mu               sync.RWMutex
+	expectedSynced   int
+	syncedNamespaces map[string]struct{}
+	lastCheck        time.Time
+	leaderElection   bool
+	leading          bool
+}
+
+// setExpectedSynced records how many informer caches this term expects to
+// sync (one per watched namespace, at least 1) and clears any sync state
+// left over from a previous term.
+func (h *healthState) setExpectedSynced(n int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if n < 1 {
+		n = 1
+	}
+	h.expectedSynced = n
+	h.syncedNamespaces = make(map[string]struct{}, n)
+}
+
+// resetSynced clears all recorded sync state; call this when a replica
+// loses leadership so a subsequent term starts from "not synced" instead of
+// reporting readiness left over from before.
+func (h *healthState) resetSynced() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.syncedNamespaces = nil
+}
+
+// setLeaderElection records whether this process participates in leader
+// election at all; it should be called once at startup before any replica
+// becomes a candidate.
+func (h *healthState) setLeaderElection(enabled bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.leaderElection = enabled
+}
+
+// setLeading updates whether this replica currently holds the lease.
+func (h *healthState) setLeading(leading bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.leading = leading
+}
+
+// setSynced records that ns's informer cache has synced (ns is "" for a
+// single cluster-wide watch).
+func (h *healthState) setSynced(ns string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.syncedNamespaces == nil {
+		h.syncedNamespaces = make(map[string]struct{})
+	}
+	h.syncedNamespaces[ns] = struct{}{}
+}
+
+func (h *healthState) recordCheck() {
+	now := time.Now()
+	h.mu.Lock()
+	h.lastCheck = now
+	h.mu.Unlock()
+	lastCheckTimestamp.Set(float64(now.Unix()))
+}
+
+func (h *healthState) isSynced() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	expected := h.expectedSynced
+	if expected < 1 {
+		expected = 1
+	}
+	return len(h.syncedNamespaces) >= expected
+}
+
+// standingBy reports whether this replica is a non-leading standby under
+// leader election, i.e. one that is never expected to sync a cache or run
+// reconciles for as long as it doesn't hold the lease.
+func (h *healthState) standingBy() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.leaderElection && !h.leading
+}
+
+func (h *healthState) timeSinceLastCheck() (time.Duration, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.lastCheck.IsZero() {
+		return 0, false
+	}
+	return time.Since(h.lastCheck), true
+}
+
+// startMetricsServer serves /metrics, /healthz and /readyz on addr. It never
+// blocks the caller: the http.Server runs in its own goroutine.
+func startMetricsServer(addr string, health *healthState, staleAfter time.Duration) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		if health.standingBy() {
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte("standing by"))
+			return
+		}
+		age, ran := health.timeSinceLastCheck()
+		if !ran {
+			http.Error(w, "no reconcile has run yet", http.StatusServiceUnavailable)
+			return
+		}
+		if age > staleAfter {
+			http.Error(w, "reconcile loop is stale", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if health.standingBy() {
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte("standing by"))
+			return
+		}
+		if !health.isSynced() {
+			http.Error(w, "informer cache not synced", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	server := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		slog.Info("starting metrics server", "addr", addr)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			slog.Error("metrics server error", "error", err)
+		}
+	}()
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// Notifier delivers a stuck-pod event to some external sink. PodStatus is the
+// canonical payload: every sink marshals (a view of) it.
+type Notifier interface {
+	Notify(ctx context.Context, ps PodStatus, reason string) error
+}
+
+// mockNotifier is the fallback sink, used when NOTIFIER is unset or names no
+// usable sink; like the old notifyAPI stub it only logs the event and waits
+// ~200ms to simulate a network call.
+type mockNotifier struct{}
+
+func (mockNotifier) Notify(ctx context.Context, ps PodStatus, reason string) error {
+	slog.Info("mock notify", "action", "notify", "namespace", ps.Namespace, "pod", ps.Name, "reason", reason)
+	select {
+	case <-time.After(200 * time.Millisecond):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// webhookNotifier POSTs the PodStatus payload as JSON to a generic endpoint,
+// optionally signing the body with HMAC-SHA256 so receivers can verify origin.
+type webhookNotifier struct {
+	url        string
+	hmacSecret string
+	headers    map[string]string
+	client     *http.Client
+}
+
+func newWebhookNotifier(url string) *webhookNotifier {
+	return &webhookNotifier{
+		url:        url,
+		hmacSecret: os.Getenv("WEBHOOK_HMAC_SECRET"),
+		headers:    parseHeaderList(os.Getenv("WEBHOOK_HEADERS")),
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (w *webhookNotifier) Notify(ctx context.Context, ps PodStatus, reason string) error {
+	body, err := json.Marshal(ps)
+	if err != nil {
+		return fmt.Errorf("marshal webhook payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range w.headers {
+		req.Header.Set(k, v)
+	}
+	if w.hmacSecret != "" {
+		req.Header.Set("X-Watchdog-Signature", signHMAC(w.hmacSecret, body))
+	}
+
+	return doAndCheck(w.client, req)
+}
+
+// slackNotifier posts an incoming-webhook formatted message with a single
+// section block summarizing the stuck pod.
+type slackNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func newSlackNotifier(url string) *slackNotifier {
+	return &slackNotifier{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (s *slackNotifier) Notify(ctx context.Context, ps PodStatus, reason string) error {
+	text := fmt.Sprintf(":warning: Pod `%s/%s` stuck (%s) for %s", ps.Namespace, ps.Name, reason, ps.Duration)
+	payload := map[string]interface{}{
+		"blocks": []map[string]interface{}{
+			{
+				"type": "section",
+				"text": map[string]string{
+					"type": "mrkdwn",
+					"text": text,
+				},
+			},
+		},
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal slack payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build slack request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	return doAndCheck(s.client, req)
+}
+
+// pagerdutyNotifier triggers an Events API v2 alert, deduplicated on
+// "namespace/pod" so repeat detections of the same pod update one incident.
+type pagerdutyNotifier struct {
+	routingKey string
+	client     *http.Client
+}
+
+const pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+func newPagerDutyNotifier(routingKey string) *pagerdutyNotifier {
+	return &pagerdutyNotifier{routingKey: routingKey, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (p *pagerdutyNotifier) Notify(ctx context.Context, ps PodStatus, reason string) error {
+	payload := map[string]interface{}{
+		"routing_key":  p.routingKey,
+		"event_action": "trigger",
+		"dedup_key":    fmt.Sprintf("%s/%s", ps.Namespace, ps.Name),
+		"payload": map[string]interface{}{
+			"summary":  fmt.Sprintf("Pod %s/%s stuck: %s (%s)", ps.Namespace, ps.Name, reason, ps.Duration),
+			"source":   "k8s-watchdog",
+			"severity": pagerDutySeverity(reason),
+			"custom_details": map[string]interface{}{
+				"namespace": ps.Namespace,
+				"pod":       ps.Name,
+				"status":    ps.Status,
+				"reason":    reason,
+				"duration":  ps.Duration,
+			},
+		},
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal pagerduty payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, pagerDutyEventsURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build pagerduty request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	return doAndCheck(p.client, req)
+}
+
+// pagerDutySeverity maps a detector reason onto a PagerDuty severity level.
+func pagerDutySeverity(reason string) string {
+	switch reason {
+	case "CrashLoopBackOff", "RestartingTooFast", "Terminating":
+		return "critical"
+	case "ImagePullBackOff", "ErrImagePull", "CreateContainerConfigError":
+		return "error"
+	default:
+		return "warning"
+	}
+}
+
+// retryNotifier wraps another Notifier with exponential backoff and jitter so
+// a transient 5xx response doesn't drop the event.
+type retryNotifier struct {
+	next    Notifier
+	backoff wait.Backoff
+}
+
+func newRetryNotifier(next Notifier) *retryNotifier {
+	return &retryNotifier{
+		next: next,
+		backoff: wait.Backoff{
+			Duration: 500 * time.Millisecond,
+			Factor:   2.0,
+			Jitter:   0.1,
+			Steps:    4,
+		},
+	}
+}
+
+func (r *retryNotifier) Notify(ctx context.Context, ps PodStatus, reason string) error {
+	var lastErr error
+	err := wait.ExponentialBackoff(r.backoff, func() (bool, error) {
+		if err := r.next.Notify(ctx, ps, reason); err != nil {
+			lastErr = err
+			slog.Warn("notify attempt failed, retrying", "namespace", ps.Namespace, "pod", ps.Name, "error", err)
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil {
+		return fmt.Errorf("notify %s/%s giving up after retries: %w", ps.Namespace, ps.Name, lastErr)
+	}
+	return nil
+}
+
+// multiNotifier fans a single event out to every configured sink, reporting
+// the first error but still notifying the rest.
+type multiNotifier []Notifier
+
+func (m multiNotifier) Notify(ctx context.Context, ps PodStatus, reason string) error {
+	var firstErr error
+	for _, n := range m {
+		if err := n.Notify(ctx, ps, reason); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// buildNotifier assembles the Notifier configured via NOTIFIER (comma
+// separated list of webhook,slack,pagerduty), each wrapped in retry with
+// backoff. Falls back to mockNotifier when NOTIFIER is unset.
+func buildNotifier() Notifier {
+	kinds := os.Getenv("NOTIFIER")
+	if kinds == "" {
+		return mockNotifier{}
+	}
+
+	var sinks []Notifier
+	for _, kind := range strings.Split(kinds, ",") {
+		switch strings.TrimSpace(kind) {
+		case "webhook":
+			url := os.Getenv("WEBHOOK_URL")
+			if url == "" {
+				slog.Warn("NOTIFIER includes webhook but WEBHOOK_URL is empty, skipping")
+				continue
+			}
+			sinks = append(sinks, newRetryNotifier(newWebhookNotifier(url)))
+		case "slack":
+			url := os.Getenv("SLACK_URL")
+			if url == "" {
+				slog.Warn("NOTIFIER includes slack but SLACK_URL is empty, skipping")
+				continue
+			}
+			sinks = append(sinks, newRetryNotifier(newSlackNotifier(url)))
+		case "pagerduty":
+			key := os.Getenv("PAGERDUTY_ROUTING_KEY")
+			if key == "" {
+				slog.Warn("NOTIFIER includes pagerduty but PAGERDUTY_ROUTING_KEY is empty, skipping")
+				continue
+			}
+			sinks = append(sinks, newRetryNotifier(newPagerDutyNotifier(key)))
+		case "":
+			// пропускаем пустые элементы из "a,,b"
+		default:
+			slog.Warn("unknown NOTIFIER kind, ignoring", "kind", kind)
+		}
+	}
+
+	if len(sinks) == 0 {
+		return mockNotifier{}
+	}
+	if len(sinks) == 1 {
+		return sinks[0]
+	}
+	return multiNotifier(sinks)
+}
+
+func doAndCheck(client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request to %s: %w", req.URL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned status %d", req.URL, resp.StatusCode)
+	}
+	return nil
+}
+
+func signHMAC(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// parseHeaderList turns "K1=V1,K2=V2" into a header map, ignoring malformed entries.
+func parseHeaderList(raw string) map[string]string {
+	headers := make(map[string]string)
+	if raw == "" {
+		return headers
+	}
+	for _, pair := range strings.Split(raw, ",") {
+		kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			continue
+		}
+		headers[kv[0]] = kv[1]
+	}
+	return headers
+}
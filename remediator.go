@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/events"
+)
+
+// RemediationAction identifies the action a Remediator takes against a
+// confirmed-stuck pod.
+type RemediationAction string
+
+const (
+	ActionDeletePod   RemediationAction = "DeletePod"
+	ActionEvictPod    RemediationAction = "EvictPod"
+	ActionAnnotatePod RemediationAction = "AnnotatePod"
+	ActionCordonNode  RemediationAction = "CordonNode"
+	ActionNone        RemediationAction = "None"
+)
+
+// Remediator takes a configured action against a pod confirmed stuck by a
+// PodProblemDetector. It defaults to DRY_RUN so a fresh deployment never
+// touches cluster state until an operator opts in, and it protects
+// kube-system and any other deny-listed namespace regardless of config.
+type Remediator struct {
+	clientset kubernetes.Interface
+	recorder  events.EventRecorder
+
+	dryRun      bool
+	action      RemediationAction
+	gracePeriod int64
+	allowlist   map[string]struct{} // пусто = разрешены все неймспейсы, кроме deny-листа
+	denylist    map[string]struct{}
+
+	maxPerMinute int
+	rateMu       sync.Mutex
+	windowStart  time.Time
+	windowCounts map[string]int
+
+	cordonThreshold int
+	cordonWindow    time.Duration
+	cordonMu        sync.Mutex
+	nodeProblems    map[string]map[string]time.Time // node -> "ns/pod/reason" -> last seen
+}
+
+func NewRemediator(clientset kubernetes.Interface, recorder events.EventRecorder) *Remediator {
+	return &Remediator{
+		clientset:       clientset,
+		recorder:        recorder,
+		dryRun:          getEnvBool("DRY_RUN", true),
+		action:          RemediationAction(getEnv("REMEDIATION_ACTION", string(ActionAnnotatePod))),
+		gracePeriod:     int64(getIntEnv("REMEDIATION_GRACE_PERIOD_SECONDS", 30)),
+		allowlist:       parseNamespaceSet(os.Getenv("REMEDIATION_NAMESPACE_ALLOWLIST")),
+		denylist:        buildDenylist(os.Getenv("REMEDIATION_NAMESPACE_DENYLIST")),
+		maxPerMinute:    int(getIntEnv("REMEDIATION_MAX_PER_MINUTE", 5)),
+		windowCounts:    make(map[string]int),
+		cordonThreshold: int(getIntEnv("CORDON_NODE_THRESHOLD", 3)),
+		cordonWindow:    getMinutesEnv("CORDON_NODE_WINDOW", 10*time.Minute),
+		nodeProblems:    make(map[string]map[string]time.Time),
+	}
+}
+
+func parseNamespaceSet(csv string) map[string]struct{} {
+	set := make(map[string]struct{})
+	for _, ns := range strings.Split(csv, ",") {
+		ns = strings.TrimSpace(ns)
+		if ns != "" {
+			set[ns] = struct{}{}
+		}
+	}
+	return set
+}
+
+// buildDenylist always protects kube-system, plus whatever the operator adds.
+func buildDenylist(csv string) map[string]struct{} {
+	set := parseNamespaceSet(csv)
+	set["kube-system"] = struct{}{}
+	return set
+}
+
+// Remediate applies the configured action to pod, subject to the
+// allow/deny-list and the per-namespace rate limit, then checks whether
+// enough pods on pod's node have tripped detectors recently to cordon it.
+func (r *Remediator) Remediate(ctx context.Context, pod *corev1.Pod, reason string) error {
+	ns := pod.Namespace
+
+	if _, denied := r.denylist[ns]; denied {
+		slog.Info("remediation skipped: namespace is deny-listed", "namespace", ns, "pod", pod.Name)
+		return nil
+	}
+	if len(r.allowlist) > 0 {
+		if _, allowed := r.allowlist[ns]; !allowed {
+			slog.Info("remediation skipped: namespace not in allow-list", "namespace", ns, "pod", pod.Name)
+			return nil
+		}
+	}
+	if !r.allowRate(ns) {
+		slog.Warn("remediation skipped: rate limit exceeded", "namespace", ns, "pod", pod.Name, "max_per_minute", r.maxPerMinute)
+		return nil
+	}
+
+	if r.dryRun {
+		slog.Info("dry-run remediation", "action", string(r.action), "namespace", ns, "pod", pod.Name, "reason", reason)
+		remediationTotal.WithLabelValues(string(r.action), "dry_run").Inc()
+		r.recordEvent(pod, "DryRun"+string(r.action), fmt.Sprintf("would have run %s (reason=%s)", r.action, reason))
+		r.maybeCordon(ctx, pod, reason)
+		return nil
+	}
+
+	var err error
+	switch r.action {
+	case ActionDeletePod:
+		err = r.deletePod(ctx, pod)
+	case ActionEvictPod:
+		err = r.evictPod(ctx, pod)
+	case ActionAnnotatePod:
+		err = r.annotatePod(ctx, pod, reason)
+	case ActionNone:
+		// только уведомление, без воздействия на под
+	default:
+		err = fmt.Errorf("unknown REMEDIATION_ACTION %q", r.action)
+	}
+
+	if err != nil {
+		remediationTotal.WithLabelValues(string(r.action), "error").Inc()
+		r.recordEvent(pod, "RemediationFailed", fmt.Sprintf("%s failed: %v", r.action, err))
+		return err
+	}
+
+	remediationTotal.WithLabelValues(string(r.action), "success").Inc()
+	if r.action != ActionNone {
+		r.recordEvent(pod, string(r.action), fmt.Sprintf("reason=%s", reason))
+	}
+	r.maybeCordon(ctx, pod, reason)
+	return nil
+}
+
+func (r *Remediator) allowRate(ns string) bool {
+	r.rateMu.Lock()
+	defer r.rateMu.Unlock()
+
+	now := time.Now()
+	if now.Sub(r.windowStart) > time.Minute {
+		r.windowStart = now
+		r.windowCounts = make(map[string]int)
+	}
+	if r.windowCounts[ns] >= r.maxPerMinute {
+		return false
+	}
+	r.windowCounts[ns]++
+	return true
+}
+
+func (r *Remediator) deletePod(ctx context.Context, pod *corev1.Pod) error {
+	grace := r.gracePeriod
+	err := r.clientset.CoreV1().Pods(pod.Namespace).Delete(ctx, pod.Name, metav1.DeleteOptions{GracePeriodSeconds: &grace})
+	if err != nil {
+		return fmt.Errorf("delete pod %s/%s: %w", pod.Namespace, pod.Name, err)
+	}
+	slog.Info("deleted stuck pod", "action", string(ActionDeletePod), "namespace", pod.Namespace, "pod", pod.Name)
+	return nil
+}
+
+// evictPod goes through the policy/v1 Eviction subresource instead of a plain
+// delete so PodDisruptionBudgets are honored.
+func (r *Remediator) evictPod(ctx context.Context, pod *corev1.Pod) error {
+	grace := r.gracePeriod
+	eviction := &policyv1.Eviction{
+		ObjectMeta:    metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+		DeleteOptions: &metav1.DeleteOptions{GracePeriodSeconds: &grace},
+	}
+	if err := r.clientset.PolicyV1().Evictions(pod.Namespace).Evict(ctx, eviction); err != nil {
+		return fmt.Errorf("evict pod %s/%s: %w", pod.Namespace, pod.Name, err)
+	}
+	slog.Info("evicted stuck pod", "action", string(ActionEvictPod), "namespace", pod.Namespace, "pod", pod.Name)
+	return nil
+}
+
+func (r *Remediator) annotatePod(ctx context.Context, pod *corev1.Pod, reason string) error {
+	patch := fmt.Sprintf(
+		`{"metadata":{"annotations":{"k8s-watchdog/stuck-reason":%q,"k8s-watchdog/detected-at":%q}}}`,
+		reason, time.Now().UTC().Format(time.RFC3339),
+	)
+	_, err := r.clientset.CoreV1().Pods(pod.Namespace).Patch(ctx, pod.Name, types.MergePatchType, []byte(patch), metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("annotate pod %s/%s: %w", pod.Namespace, pod.Name, err)
+	}
+	slog.Info("annotated stuck pod", "action", string(ActionAnnotatePod), "namespace", pod.Namespace, "pod", pod.Name)
+	return nil
+}
+
+func (r *Remediator) cordonNode(ctx context.Context, nodeName string) error {
+	patch := []byte(`{"spec":{"unschedulable":true}}`)
+	_, err := r.clientset.CoreV1().Nodes().Patch(ctx, nodeName, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("cordon node %s: %w", nodeName, err)
+	}
+	slog.Info("cordoned node", "action", string(ActionCordonNode), "node", nodeName)
+	return nil
+}
+
+// maybeCordon tracks distinct "ns/pod/reason" hits per node within
+// cordonWindow and cordons the node once cordonThreshold is reached.
+func (r *Remediator) maybeCordon(ctx context.Context, pod *corev1.Pod, reason string) {
+	node := pod.Spec.NodeName
+	if r.cordonThreshold <= 0 || node == "" {
+		return
+	}
+
+	key := fmt.Sprintf("%s/%s/%s", pod.Namespace, pod.Name, reason)
+	now := time.Now()
+
+	r.cordonMu.Lock()
+	hits, ok := r.nodeProblems[node]
+	if !ok {
+		hits = make(map[string]time.Time)
+		r.nodeProblems[node] = hits
+	}
+	for k, t := range hits {
+		if now.Sub(t) > r.cordonWindow {
+			delete(hits, k)
+		}
+	}
+	hits[key] = now
+	count := len(hits)
+	r.cordonMu.Unlock()
+
+	if count < r.cordonThreshold {
+		return
+	}
+
+	slog.Info("node has repeated stuck pods, cordoning", "node", node, "count", count, "window", r.cordonWindow.String())
+	if r.dryRun {
+		slog.Info("dry-run cordon", "action", string(ActionCordonNode), "node", node)
+		return
+	}
+	if err := r.cordonNode(ctx, node); err != nil {
+		slog.Warn("cordon node failed", "action", string(ActionCordonNode), "node", node, "error", err)
+		remediationTotal.WithLabelValues(string(ActionCordonNode), "error").Inc()
+		return
+	}
+	remediationTotal.WithLabelValues(string(ActionCordonNode), "success").Inc()
+	r.recordEvent(pod, "CordonNode", fmt.Sprintf("cordoned node %s after %d stuck pods", node, count))
+}
+
+func (r *Remediator) recordEvent(pod *corev1.Pod, reason, note string) {
+	if r.recorder == nil {
+		return
+	}
+	r.recorder.Eventf(pod, nil, corev1.EventTypeWarning, reason, "Remediate", note)
+}
+
+// newEventRecorder starts an EventBroadcasterAdapter recording to the
+// events.k8s.io sink and returns both the adapter and the derived recorder.
+// StartRecordingToSink(stopCh) only stops the adapter's own events.k8s.io
+// watch; the adapter also unconditionally spins up a legacy
+// record.EventBroadcaster internally, whose goroutines are only released by
+// calling the adapter's Shutdown() method. The caller must do so when its
+// run context ends — see PodController.broadcaster.
+func newEventRecorder(clientset kubernetes.Interface, stopCh <-chan struct{}) (events.EventBroadcasterAdapter, events.EventRecorder) {
+	broadcaster := events.NewEventBroadcasterAdapter(clientset)
+	broadcaster.StartRecordingToSink(stopCh)
+	return broadcaster, broadcaster.NewRecorder("k8s-watchdog")
+}
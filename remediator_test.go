@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRemediatorAllowRate(t *testing.T) {
+	r := &Remediator{maxPerMinute: 2, windowCounts: make(map[string]int)}
+
+	if !r.allowRate("default") {
+		t.Fatal("first call in a fresh window should be allowed")
+	}
+	if !r.allowRate("default") {
+		t.Fatal("second call within maxPerMinute should be allowed")
+	}
+	if r.allowRate("default") {
+		t.Fatal("third call should exceed the per-namespace limit")
+	}
+
+	// A different namespace has its own independent counter.
+	if !r.allowRate("other") {
+		t.Fatal("a different namespace should not share the exhausted namespace's budget")
+	}
+}
+
+func TestRemediatorAllowRateWindowReset(t *testing.T) {
+	r := &Remediator{maxPerMinute: 1, windowCounts: make(map[string]int)}
+
+	if !r.allowRate("default") {
+		t.Fatal("first call should be allowed")
+	}
+	if r.allowRate("default") {
+		t.Fatal("second call within the same minute should be rate limited")
+	}
+
+	// Force the window to look stale so the next call rolls it over.
+	r.windowStart = time.Now().Add(-2 * time.Minute)
+	if !r.allowRate("default") {
+		t.Fatal("call after the window rolls over should be allowed again")
+	}
+}